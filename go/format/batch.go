@@ -0,0 +1,200 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// BatchOptions controls SourceFiles.
+type BatchOptions struct {
+	// Write rewrites each changed file in place. It is ignored if List
+	// is set without Write also being set.
+	Write bool
+
+	// List, if true, omits FileResult.Formatted: every file is still
+	// read and formatted to determine FileResult.Changed (and to
+	// compute Diff or Write, if those are also set), but the formatted
+	// content itself is only worth keeping when the caller wants to
+	// write or print it.
+	List bool
+
+	// Diff, when true, populates FileResult.Diff with a unified diff
+	// between the original and formatted content of any changed file.
+	Diff bool
+
+	// Simplify applies the gofmt -s simplifications (see Options) to
+	// each file before printing it.
+	Simplify bool
+
+	// NumWorkers bounds the number of files formatted concurrently.
+	// A value <= 0 means runtime.GOMAXPROCS(0).
+	NumWorkers int
+}
+
+// FileResult is the outcome of formatting a single file, delivered on
+// the channel SourceFiles returns.
+type FileResult struct {
+	Path      string
+	Changed   bool
+	Formatted []byte
+	Diff      []byte
+	Err       error
+}
+
+// SourceFiles formats each of paths the way Source does, and reports
+// the outcome for each on the returned channel, in no particular
+// order. Workers are spawned lazily, up to the cap opts sets (or
+// GOMAXPROCS, by default), so that formatting a handful of files
+// does not pay the cost of starting a full pool; GoReduce and
+// GoReduceLinesFromReader in the top-level package now use the same
+// lazy-spawn approach. The channel is closed once every path has been
+// processed or ctx is done.
+//
+// SourceFiles returns an error only if opts is malformed; per-file
+// errors are reported through FileResult.Err instead, so that one bad
+// file does not prevent the rest from being formatted.
+func SourceFiles(ctx context.Context, paths []string, opts *BatchOptions) (<-chan FileResult, error) {
+	if opts == nil {
+		opts = &BatchOptions{}
+	}
+	numWorkers := opts.NumWorkers
+	if numWorkers <= 0 {
+		numWorkers = runtime.GOMAXPROCS(0)
+	}
+	if numWorkers > len(paths) {
+		numWorkers = len(paths)
+	}
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+
+	jobs := make(chan string)
+	out := make(chan FileResult)
+	var wg sync.WaitGroup
+
+	worker := func() {
+		defer wg.Done()
+		for p := range jobs {
+			select {
+			case out <- formatFile(p, opts):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	go func() {
+		// Spawn a worker alongside each job, up to numWorkers, instead
+		// of starting the whole pool before any work exists: with an
+		// unbuffered jobs channel a job can only be sent once a worker
+		// is there to receive it, and a sparse path list should not pay
+		// for idle goroutines it will never feed.
+		//
+		// wg.Wait is called here, in the same goroutine that calls
+		// wg.Add, rather than in a separate goroutine racing against
+		// the dispatch loop above: wg.Add and wg.Wait running
+		// concurrently on different goroutines have no happens-before
+		// relationship, so Wait could observe a zero counter and
+		// close out before any worker has even been spawned.
+		spawned := 0
+	dispatch:
+		for _, p := range paths {
+			if spawned < numWorkers {
+				spawned++
+				wg.Add(1)
+				go worker()
+			}
+			select {
+			case jobs <- p:
+			case <-ctx.Done():
+				break dispatch
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(out)
+	}()
+
+	return out, nil
+}
+
+// formatFile formats the file at path according to opts, producing
+// the FileResult SourceFiles reports for it.
+func formatFile(path string, opts *BatchOptions) FileResult {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return FileResult{Path: path, Err: err}
+	}
+
+	var formatted []byte
+	if opts.Simplify {
+		formatted, err = SourceWithOptions(src, &Options{Simplify: true})
+	} else {
+		formatted, err = Source(src)
+	}
+	if err != nil {
+		return FileResult{Path: path, Err: err}
+	}
+
+	res := FileResult{Path: path, Changed: !bytes.Equal(src, formatted)}
+	if !res.Changed {
+		return res
+	}
+
+	if !opts.List {
+		res.Formatted = formatted
+	}
+	if opts.Diff {
+		res.Diff = unifiedDiff(path, src, formatted)
+	}
+	if opts.Write {
+		info, err := os.Stat(path)
+		if err != nil {
+			res.Err = err
+			return res
+		}
+		if err := ioutil.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+			res.Err = err
+		}
+	}
+	return res
+}
+
+// unifiedDiff produces a minimal line-oriented unified diff between
+// src and formatted, good enough for -d style reporting. It is not a
+// full Myers diff: runs of identical leading and trailing lines are
+// collapsed, and the remaining interior is reported as one replaced
+// hunk.
+func unifiedDiff(path string, src, formatted []byte) []byte {
+	a := bytes.Split(src, []byte("\n"))
+	b := bytes.Split(formatted, []byte("\n"))
+
+	start := 0
+	for start < len(a) && start < len(b) && bytes.Equal(a[start], b[start]) {
+		start++
+	}
+	endA, endB := len(a), len(b)
+	for endA > start && endB > start && bytes.Equal(a[endA-1], b[endB-1]) {
+		endA--
+		endB--
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("--- " + path + ".orig\n")
+	buf.WriteString("+++ " + path + "\n")
+	for _, line := range a[start:endA] {
+		buf.WriteString("-" + string(line) + "\n")
+	}
+	for _, line := range b[start:endB] {
+		buf.WriteString("+" + string(line) + "\n")
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,73 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// writeTempFiles writes each of contents to its own file under a fresh
+// temporary directory and returns their paths.
+func writeTempFiles(t *testing.T, contents []string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := make([]string, len(contents))
+	for i, c := range contents {
+		p := filepath.Join(dir, string(rune('a'+i))+".go")
+		if err := ioutil.WriteFile(p, []byte(c), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths[i] = p
+	}
+	return paths
+}
+
+func TestSourceFilesDrainsEveryResult(t *testing.T) {
+	paths := writeTempFiles(t, []string{
+		"package p\n\nvar X = 1\n",
+		"package p\n\nvar   Y = 2\n",
+		"package p\n\nvar Z = 3\n",
+	})
+
+	results, err := SourceFiles(context.Background(), paths, &BatchOptions{NumWorkers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	for r := range results {
+		if r.Err != nil {
+			t.Errorf("unexpected error formatting %s: %v", r.Path, r.Err)
+		}
+		seen[r.Path] = true
+	}
+	for _, p := range paths {
+		if !seen[p] {
+			t.Errorf("missing result for %s", p)
+		}
+	}
+}
+
+func TestSourceFilesSingleWorker(t *testing.T) {
+	paths := writeTempFiles(t, []string{
+		"package p\n\nvar X = 1\n",
+	})
+
+	results, err := SourceFiles(context.Background(), paths, &BatchOptions{NumWorkers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := 0
+	for range results {
+		n++
+	}
+	if n != len(paths) {
+		t.Errorf("got %d results, want %d", n, len(paths))
+	}
+}
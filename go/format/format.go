@@ -46,7 +46,7 @@ func Node(dst io.Writer, fset *token.FileSet, node interface{}) error {
 	}
 
 	// Sort imports if necessary.
-	if file != nil && hasUnsortedImports(file) {
+	if file != nil && hasUnsortedImports(fset, file) {
 		// Make a copy of the AST because ast.SortImports is destructive.
 		// TODO(gri) Do this more efficiently.
 		var buf bytes.Buffer
@@ -82,17 +82,24 @@ func Node(dst io.Writer, fset *token.FileSet, node interface{}) error {
 //
 func Source(src []byte) ([]byte, error) {
 	fset := token.NewFileSet()
-	file, adjust, adjustIndent, err := parse(fset, "", src, true)
+	file, adjust, adjustIndent, err := Parse(fset, "", src, true)
 	if err != nil {
 		return nil, err
 	}
+	return Format(fset, file, adjust, adjustIndent, src, config)
+}
 
+// Format renders file (as parsed by Parse, with the accompanying adjust
+// and indent values it returned) in canonical gofmt style, using src to
+// recover the leading, trailing, and indentation whitespace of partial
+// source files. cfg is the printer configuration to format with.
+func Format(fset *token.FileSet, file *ast.File, adjust SourceAdjust, adjustIndent int, src []byte, cfg printer.Config) ([]byte, error) {
 	var res []byte
 	if adjust == nil {
 		// Complete source file.
 		ast.SortImports(fset, file)
 		var buf bytes.Buffer
-		err := config.Fprint(&buf, fset, file)
+		err := cfg.Fprint(&buf, fset, file)
 		if err != nil {
 			return nil, err
 		}
@@ -132,7 +139,6 @@ func Source(src []byte) ([]byte, error) {
 
 		// Format the source.
 		// Write it without any leading and trailing space.
-		cfg := config
 		cfg.Indent = indent + adjustIndent
 		var buf bytes.Buffer
 		err := cfg.Fprint(&buf, fset, file)
@@ -152,7 +158,11 @@ func Source(src []byte) ([]byte, error) {
 	return res, nil
 }
 
-func hasUnsortedImports(file *ast.File) bool {
+// hasUnsortedImports reports whether file's import declarations are
+// not already in the canonical order ast.SortImports would produce,
+// so that callers can skip the costly print/reparse/sort round trip
+// when a file's imports are already clean.
+func hasUnsortedImports(fset *token.FileSet, file *ast.File) bool {
 	for _, d := range file.Decls {
 		d, ok := d.(*ast.GenDecl)
 		if !ok || d.Tok != token.IMPORT {
@@ -160,28 +170,129 @@ func hasUnsortedImports(file *ast.File) bool {
 			// Imports are always first.
 			return false
 		}
-		if d.Lparen.IsValid() {
-			// For now assume all grouped imports are unsorted.
-			// TODO(gri) Should check if they are sorted already.
-			return true
+		if !d.Lparen.IsValid() {
+			// Ungrouped imports are sorted by default.
+			continue
+		}
+		for _, run := range importRuns(fset, d.Specs) {
+			if !runIsSorted(run) {
+				return true
+			}
 		}
-		// Ungrouped imports are sorted by default.
 	}
 	return false
 }
 
-// parse parses src, which was read from filename,
-// as a Go source file or statement list.
-func parse(fset *token.FileSet, filename string, src []byte, stdin bool) (*ast.File, func(src []byte, indent int) []byte, int, error) {
+// importRuns splits specs, the Specs of a single parenthesized import
+// declaration, into the contiguous runs ast.SortImports treats
+// independently: a run ends wherever a blank source line separates
+// one spec from the next.
+func importRuns(fset *token.FileSet, specs []ast.Spec) [][]*ast.ImportSpec {
+	var runs [][]*ast.ImportSpec
+	var run []*ast.ImportSpec
+	prevEnd := -1
+	for _, s := range specs {
+		spec := s.(*ast.ImportSpec)
+		line := fset.Position(spec.Pos()).Line
+		if prevEnd >= 0 && line > prevEnd+1 {
+			runs = append(runs, run)
+			run = nil
+		}
+		run = append(run, spec)
+		prevEnd = fset.Position(spec.End()).Line
+		if c := spec.Comment; c != nil {
+			if end := fset.Position(c.End()).Line; end > prevEnd {
+				prevEnd = end
+			}
+		}
+	}
+	if len(run) > 0 {
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// runIsSorted reports whether run is already in the order
+// ast.SortImports would leave it in: grouped by blank line (the caller
+// has already split on that), non-decreasing by import path, and,
+// among specs sharing a path, ordered the same way ast.SortImports'
+// tie-break (explicit name, then comment text) would order them, with
+// no two adjacent specs naming the same path under the same name -
+// ast.SortImports removes that duplicate, so the fast path must not
+// mistake it for an already-clean, merely-repeated import.
+func runIsSorted(run []*ast.ImportSpec) bool {
+	for i := 1; i < len(run); i++ {
+		if importLess(run[i], run[i-1]) {
+			return false
+		}
+		if run[i].Path.Value == run[i-1].Path.Value && importSpecName(run[i]) == importSpecName(run[i-1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// importLess reports whether a should sort before b, using the same
+// key ast.SortImports uses: import path first, then the spec's
+// explicit local name, then its trailing line comment.
+func importLess(a, b *ast.ImportSpec) bool {
+	pa, pb := a.Path.Value, b.Path.Value
+	if pa != pb {
+		return pa < pb
+	}
+	na, nb := importSpecName(a), importSpecName(b)
+	if na != nb {
+		return na < nb
+	}
+	return importSpecComment(a) < importSpecComment(b)
+}
+
+func importSpecName(s *ast.ImportSpec) string {
+	if s.Name != nil {
+		return s.Name.Name
+	}
+	return ""
+}
+
+func importSpecComment(s *ast.ImportSpec) string {
+	if s.Comment != nil {
+		return s.Comment.Text()
+	}
+	return ""
+}
+
+// SourceAdjust rewrites the formatted output buf (indented by indent
+// tabs) of a partial source file back into the shape the caller's
+// fragment had before the synthetic wrapping Parse added to make it
+// parseable, trimming that wrapping and any resulting leading or
+// trailing space. A nil SourceAdjust means no such wrapping was
+// needed: file is a complete, unmodified source file.
+type SourceAdjust func(buf []byte, indent int) []byte
+
+// Parse parses src, which was read from filename, as a Go source file,
+// a list of declarations, or a list of statements, trying each in
+// turn. fragmentOk enables the declaration- and statement-list
+// fallbacks; callers that know src is always a complete source file
+// (for example because it was read from disk rather than typed at a
+// prompt) should pass false.
+//
+// If src parses as a complete source file, Parse returns it together
+// with a nil SourceAdjust and an adjustIndent of 0. Otherwise, Parse
+// wraps src in a synthetic package clause (and, for a statement list,
+// a function body) so that it can be parsed, and returns the resulting
+// file along with a SourceAdjust that undoes the wrapping and the
+// indentation adjustment, relative to src's own indentation, that the
+// wrapping introduced.
+func Parse(fset *token.FileSet, filename string, src []byte, fragmentOk bool) (*ast.File, SourceAdjust, int, error) {
 	// Try as whole source file.
 	file, err := parser.ParseFile(fset, filename, src, parserMode)
 	if err == nil {
 		return file, nil, 0, nil
 	}
 	// If the error is that the source file didn't begin with a
-	// package line and this is standard input, fall through to
+	// package line and fragments are allowed, fall through to
 	// try as a source fragment.  Stop and return on any other error.
-	if !stdin || !strings.Contains(err.Error(), "expected 'package'") {
+	if !fragmentOk || !strings.Contains(err.Error(), "expected 'package'") {
 		return nil, nil, 0, err
 	}
 
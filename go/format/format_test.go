@@ -0,0 +1,188 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestHasUnsortedImportsSorted(t *testing.T) {
+	const src = `package p
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/tools/imports"
+)
+
+var _ = fmt.Sprint
+var _ = os.Getenv
+var _ = imports.Options{}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parserMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasUnsortedImports(fset, file) {
+		t.Error("hasUnsortedImports reported an already-sorted, already-grouped file as unsorted")
+	}
+}
+
+func TestHasUnsortedImportsUnsorted(t *testing.T) {
+	const src = `package p
+
+import (
+	"os"
+	"fmt"
+)
+
+var _ = fmt.Sprint
+var _ = os.Getenv
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parserMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasUnsortedImports(fset, file) {
+		t.Error("hasUnsortedImports missed a run that is not lexically ordered")
+	}
+}
+
+func TestHasUnsortedImportsSecondRunUnsorted(t *testing.T) {
+	// The first blank-line-delimited run is already sorted; only the
+	// second is not. hasUnsortedImports must check every run, not just
+	// stop after the first.
+	const src = `package p
+
+import (
+	"bytes"
+
+	"os"
+	"fmt"
+)
+
+var _ = bytes.NewReader
+var _ = fmt.Sprint
+var _ = os.Getenv
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parserMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasUnsortedImports(fset, file) {
+		t.Error("hasUnsortedImports missed an unsorted second run")
+	}
+}
+
+func TestHasUnsortedImportsDuplicate(t *testing.T) {
+	// A non-decreasing run that repeats a path is not "sorted" in the
+	// sense that matters here: ast.SortImports would still collapse
+	// the duplicate, so the fast path must not skip that dedup.
+	const src = `package p
+
+import (
+	"fmt"
+	"fmt"
+)
+
+var _ = fmt.Sprint
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parserMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasUnsortedImports(fset, file) {
+		t.Error("hasUnsortedImports missed a duplicate import within a run")
+	}
+}
+
+func TestNodeDropsDuplicateImport(t *testing.T) {
+	const src = `package p
+
+import (
+	"fmt"
+	"fmt"
+)
+
+var _ = fmt.Sprint
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parserMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Node(&buf, fset, file); err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(buf.Bytes(), []byte(`"fmt"`)); n != 1 {
+		t.Errorf("expected the duplicate \"fmt\" import to be collapsed to one, got %d occurrences:\n%s", n, buf.Bytes())
+	}
+}
+
+// largeSortedSource returns a complete, already gofmt-sorted source
+// file with n grouped, non-overlapping stdlib-shaped import paths, for
+// the BenchmarkNodeAlreadySorted/BenchmarkNodeUnsorted fast-path
+// comparison below.
+func largeSortedSource(n int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("package p\n\nimport (\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, "\t\"pkg/p%04d\"\n", i)
+	}
+	buf.WriteString(")\n\nfunc f() {}\n")
+	return buf.Bytes()
+}
+
+// BenchmarkNodeAlreadySorted measures format.Node on a large file whose
+// imports are already canonical, the case hasUnsortedImports's real
+// scan lets skip the print/reparse/sort round trip entirely.
+func BenchmarkNodeAlreadySorted(b *testing.B) {
+	src := largeSortedSource(500)
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parserMode)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		if err := Node(&buf, fset, file); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkNodeUnsorted measures the same file with its first two
+// imports swapped, forcing the print/reparse/sort round trip on every
+// call; the gap between this and BenchmarkNodeAlreadySorted is the win
+// the real scan buys on files that don't need it.
+func BenchmarkNodeUnsorted(b *testing.B) {
+	src := largeSortedSource(500)
+	src = bytes.Replace(src, []byte("\"pkg/p0000\"\n\t\"pkg/p0001\"\n"), []byte("\"pkg/p0001\"\n\t\"pkg/p0000\"\n"), 1)
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parserMode)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		if err := Node(&buf, fset, file); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,513 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Resolver maps an identifier used but not declared in a source file
+// (e.g. "fmt" in a reference to fmt.Println) to the import path that
+// should be added to satisfy it. Implementations may consult a local
+// module, a GOPATH workspace, a vendor directory, or a module proxy.
+//
+// Resolve returns ok == false if name could not be resolved; in that
+// case the identifier is left unresolved and no import is added for it.
+type Resolver interface {
+	Resolve(ctx context.Context, name string) (path string, ok bool)
+}
+
+// ImportOptions controls the behavior of SourceWithImports and
+// NodeWithImports.
+type ImportOptions struct {
+	// FormatOnly, if true, disables import resolution: the source is
+	// formatted and its existing imports sorted and grouped exactly as
+	// Source and Node already do, but no imports are added or removed.
+	FormatOnly bool
+
+	// LocalPrefix is a comma-separated list of import path prefixes
+	// that belong to the caller's own module or organization. Imports
+	// matching one of these prefixes are placed in their own group,
+	// after the standard library and third-party groups.
+	LocalPrefix string
+
+	// Resolver is consulted for every identifier that is referenced
+	// but has no corresponding import, after the standard library
+	// source tree has already been searched. It may be nil, in which
+	// case unresolved identifiers outside of GOROOT are left alone.
+	Resolver Resolver
+
+	// Context bounds the work done by Resolver. If nil,
+	// context.Background() is used.
+	Context context.Context
+}
+
+// SourceWithImports formats src like Source, and additionally adds
+// imports for identifiers that are referenced but not imported, and
+// removes imports that are no longer used. opts may be nil, which is
+// equivalent to a zero ImportOptions. Unlike Source, src must be a
+// complete Go source file: a list of declarations or statements is
+// not accepted and yields a parse error.
+func SourceWithImports(src []byte, opts *ImportOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &ImportOptions{}
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parserMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.FormatOnly {
+		file, err = fixImports(fset, file, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ast.SortImports(fset, file)
+
+	var buf bytes.Buffer
+	if err := config.Fprint(&buf, fset, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// NodeWithImports formats node like Node, and additionally adds and
+// removes imports the same way SourceWithImports does. node must be an
+// *ast.File; for any other node type (including a *printer.CommentedNode,
+// which carries no room to splice a new import block into) NodeWithImports
+// behaves exactly like Node. opts may be nil, which is equivalent to a
+// zero ImportOptions.
+func NodeWithImports(dst io.Writer, fset *token.FileSet, node interface{}, opts *ImportOptions) error {
+	if opts == nil {
+		opts = &ImportOptions{}
+	}
+
+	file, _ := node.(*ast.File)
+	if file == nil {
+		return Node(dst, fset, node)
+	}
+
+	if !opts.FormatOnly {
+		fixed, err := fixImports(fset, file, opts)
+		if err != nil {
+			return err
+		}
+		file = fixed
+		node = file
+	}
+
+	return Node(dst, fset, node)
+}
+
+// importGroup classifies an import path into one of the groups
+// goimports separates with a blank line: standard library, local
+// (matching one of localPrefixes), or third-party.
+type importGroup int
+
+const (
+	groupStdlib importGroup = iota
+	groupThirdParty
+	groupLocal
+	numImportGroups
+)
+
+func classifyImport(path string, localPrefixes []string) importGroup {
+	for _, p := range localPrefixes {
+		if p != "" && (path == p || strings.HasPrefix(path, p+"/")) {
+			return groupLocal
+		}
+	}
+	if _, ok := stdlibIndex()[path]; ok {
+		return groupStdlib
+	}
+	// Fall back to the historical heuristic: a path with no dot in its
+	// first component is assumed to be standard library.
+	if first := strings.SplitN(path, "/", 2)[0]; !strings.Contains(first, ".") {
+		return groupStdlib
+	}
+	return groupThirdParty
+}
+
+// namedImport is the information fixImports needs to re-emit an
+// import spec, whether it already existed in the source or is being
+// newly added: the import path, and the explicit local name the
+// source bound it to, if any ("" for a plain import, "_" for a blank
+// import, "." for a dot import).
+type namedImport struct {
+	name, path string
+}
+
+// fixImports returns a copy of file with unused import specs dropped
+// and specs added for identifiers that are referenced but not
+// imported, then regroups the result into stdlib/third-party/local
+// blocks. If file has no import declarations and nothing ends up
+// needing to be added, it returns file unmodified, so that
+// format.Node's canonical fast path (skip the print/reparse round
+// trip when nothing moved) still applies downstream.
+//
+// Unlike editing ast.ImportSpecs in place, fixImports rebuilds the
+// entire import block as text and reparses it: new specs carry no
+// source position, so the printer has no line-gap information to
+// decide which blank-line-delimited group they belong to, and
+// splicing fresh, correctly positioned text is the only way to get
+// the stdlib/third-party/local grouping right. This runs even when
+// nothing was added or removed, since an existing import block may
+// still be ungrouped (e.g. everything in one run, as a human would
+// write it before goimports ever touched the file).
+func fixImports(fset *token.FileSet, file *ast.File, opts *ImportOptions) (*ast.File, error) {
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	used := usedPackages(file)
+
+	var kept []namedImport
+	var doc *ast.CommentGroup
+	hadImports := false
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT || isCgoImportDecl(gd) {
+			// The cgo pseudo-import is left untouched entirely: its
+			// preamble comment is C source, not documentation, and
+			// relocating or dropping it would silently break the cgo
+			// build rather than just look wrong.
+			continue
+		}
+		hadImports = true
+		if doc == nil {
+			doc = gd.Doc
+		}
+		for _, spec := range gd.Specs {
+			is := spec.(*ast.ImportSpec)
+			name := importedName(is)
+			if name != "_" && name != "." && !used[name] {
+				continue // unused import dropped
+			}
+			path, err := strconv.Unquote(is.Path.Value)
+			if err != nil {
+				return nil, err
+			}
+			explicit := ""
+			if is.Name != nil {
+				explicit = is.Name.Name
+			}
+			kept = append(kept, namedImport{explicit, path})
+		}
+	}
+
+	if missing := unresolvedPackages(file, used); len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			path, ok := resolveImport(ctx, name, opts.Resolver)
+			if !ok {
+				continue
+			}
+			explicit := ""
+			if lastPathComponent(path) != name {
+				explicit = name
+			}
+			kept = append(kept, namedImport{explicit, path})
+		}
+	}
+
+	if len(kept) == 0 && !hadImports {
+		return file, nil
+	}
+
+	var localPrefixes []string
+	for _, p := range strings.Split(opts.LocalPrefix, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			localPrefixes = append(localPrefixes, p)
+		}
+	}
+
+	var groups [numImportGroups][]namedImport
+	for _, im := range kept {
+		g := classifyImport(im.path, localPrefixes)
+		groups[g] = append(groups[g], im)
+	}
+	for _, g := range groups {
+		sort.Slice(g, func(i, j int) bool { return g[i].path < g[j].path })
+	}
+
+	src, err := renderWithoutImports(fset, file)
+	if err != nil {
+		return nil, err
+	}
+	src = spliceImportBlock(src, groups[:], doc)
+
+	return parser.ParseFile(fset, "", src, parserMode)
+}
+
+// isCgoImportDecl reports whether gd contains the cgo pseudo-import
+// "C". A decl shaped this way is never stripped or regrouped: import
+// "C" must stay exactly where the author put it, with the preceding
+// comment (the C preamble cgo compiles, not a doc comment) directly
+// attached, or the cgo build breaks.
+func isCgoImportDecl(gd *ast.GenDecl) bool {
+	for _, spec := range gd.Specs {
+		is, ok := spec.(*ast.ImportSpec)
+		if !ok {
+			continue
+		}
+		if path, err := strconv.Unquote(is.Path.Value); err == nil && path == "C" {
+			return true
+		}
+	}
+	return false
+}
+
+// renderWithoutImports prints file with every non-cgo top-level import
+// declaration removed, leaving a gap (right after the package clause)
+// for spliceImportBlock to fill back in. Any comment group that
+// belonged to one of the removed decls is dropped along with it,
+// rather than left in file.Comments for the printer to reattach to
+// whatever declaration follows - doc carries the one comment worth
+// keeping back into the spliced block instead.
+func renderWithoutImports(fset *token.FileSet, file *ast.File) ([]byte, error) {
+	stripped := *file
+	decls := make([]ast.Decl, 0, len(file.Decls))
+	type span struct{ start, end token.Pos }
+	var removed []span
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if ok && gd.Tok == token.IMPORT && !isCgoImportDecl(gd) {
+			start := gd.Pos()
+			if gd.Doc != nil {
+				start = gd.Doc.Pos()
+			}
+			removed = append(removed, span{start, gd.End()})
+			continue
+		}
+		decls = append(decls, decl)
+	}
+	stripped.Decls = decls
+
+	if len(removed) > 0 {
+		comments := make([]*ast.CommentGroup, 0, len(file.Comments))
+		for _, cg := range file.Comments {
+			inRemoved := false
+			for _, r := range removed {
+				if cg.Pos() >= r.start && cg.End() <= r.end {
+					inRemoved = true
+					break
+				}
+			}
+			if !inRemoved {
+				comments = append(comments, cg)
+			}
+		}
+		stripped.Comments = comments
+	}
+
+	var buf bytes.Buffer
+	if err := config.Fprint(&buf, fset, &stripped); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// spliceImportBlock inserts a grouped import declaration for groups
+// (stdlib, third-party, local, in that order, blank line between any
+// two non-empty groups) right after the package clause line of src.
+// doc, if non-nil, is the Doc comment of the import declaration
+// fixImports found in the original source, reattached directly above
+// the new block so it survives the rebuild.
+func spliceImportBlock(src []byte, groups [][]namedImport, doc *ast.CommentGroup) []byte {
+	any := false
+	for _, g := range groups {
+		if len(g) > 0 {
+			any = true
+			break
+		}
+	}
+	if !any {
+		return src
+	}
+
+	nl := bytes.IndexByte(src, '\n')
+	if nl < 0 {
+		nl = len(src) - 1
+	}
+	header, rest := src[:nl+1], src[nl+1:]
+
+	var block bytes.Buffer
+	block.WriteString("\n")
+	if doc != nil {
+		for _, c := range doc.List {
+			block.WriteString(c.Text)
+			block.WriteString("\n")
+		}
+	}
+	block.WriteString("import (\n")
+	first := true
+	for _, g := range groups {
+		if len(g) == 0 {
+			continue
+		}
+		if !first {
+			block.WriteString("\n")
+		}
+		first = false
+		for _, im := range g {
+			if im.name != "" {
+				fmt.Fprintf(&block, "\t%s %s\n", im.name, strconv.Quote(im.path))
+			} else {
+				fmt.Fprintf(&block, "\t%s\n", strconv.Quote(im.path))
+			}
+		}
+	}
+	block.WriteString(")\n")
+
+	out := make([]byte, 0, len(header)+block.Len()+len(rest))
+	out = append(out, header...)
+	out = append(out, block.Bytes()...)
+	out = append(out, rest...)
+	return out
+}
+
+// usedPackages returns the set of package identifiers (qualifiers of a
+// selector expression) referenced anywhere in file's declarations,
+// excluding the import declarations themselves.
+func usedPackages(file *ast.File) map[string]bool {
+	used := make(map[string]bool)
+	for _, decl := range file.Decls {
+		if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.IMPORT {
+			continue
+		}
+		ast.Inspect(decl, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			if id, ok := sel.X.(*ast.Ident); ok {
+				used[id.Name] = true
+			}
+			return true
+		})
+	}
+	return used
+}
+
+// importedName returns the local name an ImportSpec binds: its
+// explicit name if present, otherwise the last component of its path.
+func importedName(is *ast.ImportSpec) string {
+	if is.Name != nil {
+		return is.Name.Name
+	}
+	path, err := strconv.Unquote(is.Path.Value)
+	if err != nil {
+		return ""
+	}
+	return lastPathComponent(path)
+}
+
+// lastPathComponent returns the portion of path after its final "/",
+// or path itself if it has none - i.e. the package identifier an
+// import of path binds by default.
+func lastPathComponent(path string) string {
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+// unresolvedPackages returns the subset of used identifiers that do
+// not already correspond to an import spec in file.
+func unresolvedPackages(file *ast.File, used map[string]bool) map[string]bool {
+	have := make(map[string]bool)
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			have[importedName(spec.(*ast.ImportSpec))] = true
+		}
+	}
+	missing := make(map[string]bool)
+	for name := range used {
+		if !have[name] {
+			missing[name] = true
+		}
+	}
+	return missing
+}
+
+// resolveImport looks up the import path that provides the package
+// named name. It first checks the standard library (indexed from
+// GOROOT/src by package name, since "net/http" cannot be found by
+// searching for the bare identifier "http" the way an import path
+// lookup would), then falls back to resolver, if any.
+func resolveImport(ctx context.Context, name string, resolver Resolver) (path string, ok bool) {
+	if path, ok := stdlibIndex()[name]; ok {
+		return path, true
+	}
+	if resolver != nil {
+		return resolver.Resolve(ctx, name)
+	}
+	return "", false
+}
+
+var (
+	stdlibIndexOnce sync.Once
+	stdlibIndexMap  map[string]string // package name -> import path, e.g. "http" -> "net/http"
+)
+
+// stdlibIndex lazily builds and caches a map from package name to
+// import path for every non-internal, non-command package under
+// GOROOT/src, so resolveImport can turn a bare identifier like "http"
+// into the "net/http" import path that provides it.
+func stdlibIndex() map[string]string {
+	stdlibIndexOnce.Do(func() {
+		stdlibIndexMap = make(map[string]string)
+		srcRoot := filepath.Join(build.Default.GOROOT, "src")
+		filepath.Walk(srcRoot, func(dir string, info os.FileInfo, err error) error {
+			if err != nil || !info.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(srcRoot, dir)
+			if err != nil || rel == "." {
+				return nil
+			}
+			base := filepath.Base(rel)
+			if base == "internal" || base == "testdata" || base == "vendor" || rel == "cmd" || strings.HasPrefix(rel, "cmd"+string(filepath.Separator)) {
+				return filepath.SkipDir
+			}
+			pkg, err := build.ImportDir(dir, 0)
+			if err != nil || pkg.Name == "" || pkg.Name == "main" {
+				return nil
+			}
+			if _, exists := stdlibIndexMap[pkg.Name]; !exists {
+				stdlibIndexMap[pkg.Name] = filepath.ToSlash(rel)
+			}
+			return nil
+		})
+	})
+	return stdlibIndexMap
+}
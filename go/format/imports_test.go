@@ -0,0 +1,242 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// stubResolver resolves a fixed set of names, as a test double for
+// whatever a module proxy or vendor-aware Resolver would do.
+type stubResolver map[string]string
+
+func (r stubResolver) Resolve(ctx context.Context, name string) (string, bool) {
+	path, ok := r[name]
+	return path, ok
+}
+
+func TestSourceWithImportsAdd(t *testing.T) {
+	const src = `package p
+
+func f() {
+	fmt.Println("hi")
+	os.Getenv("X")
+}
+`
+	out, err := SourceWithImports([]byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"fmt"`, `"os"`} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output missing import %s, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestSourceWithImportsAddViaResolver(t *testing.T) {
+	const src = `package p
+
+func f() {
+	widget.New()
+}
+`
+	resolver := stubResolver{"widget": "example.com/acme/widget"}
+	out, err := SourceWithImports([]byte(src), &ImportOptions{Resolver: resolver})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"example.com/acme/widget"`) {
+		t.Errorf("output missing resolved import, got:\n%s", out)
+	}
+}
+
+func TestSourceWithImportsRemove(t *testing.T) {
+	const src = `package p
+
+import (
+	"fmt"
+	"os"
+)
+
+func f() {
+	fmt.Println("hi")
+}
+`
+	out, err := SourceWithImports([]byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), `"os"`) {
+		t.Errorf("expected unused import \"os\" to be removed, got:\n%s", out)
+	}
+	if !strings.Contains(string(out), `"fmt"`) {
+		t.Errorf("expected used import \"fmt\" to survive, got:\n%s", out)
+	}
+}
+
+func TestSourceWithImportsRenamePreserved(t *testing.T) {
+	const src = `package p
+
+import (
+	renamed "fmt"
+)
+
+func f() {
+	renamed.Println("hi")
+}
+`
+	out, err := SourceWithImports([]byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `renamed "fmt"`) {
+		t.Errorf("expected rename to be preserved, got:\n%s", out)
+	}
+}
+
+func TestSourceWithImportsDotImportKept(t *testing.T) {
+	const src = `package p
+
+import (
+	. "fmt"
+)
+
+func f() {
+	Println("hi")
+}
+`
+	out, err := SourceWithImports([]byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `. "fmt"`) {
+		t.Errorf("expected dot import to survive even though Println isn't a selector use, got:\n%s", out)
+	}
+}
+
+func TestSourceWithImportsBlankImportKept(t *testing.T) {
+	const src = `package p
+
+import (
+	_ "net/http/pprof"
+)
+
+func f() {
+}
+`
+	out, err := SourceWithImports([]byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `_ "net/http/pprof"`) {
+		t.Errorf("expected blank import to survive, got:\n%s", out)
+	}
+}
+
+func TestSourceWithImportsCgoKept(t *testing.T) {
+	const src = `package p
+
+import "C"
+
+func f() {
+	C.free(nil)
+}
+`
+	out, err := SourceWithImports([]byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"C"`) {
+		t.Errorf("expected cgo pseudo-import to survive, got:\n%s", out)
+	}
+}
+
+func TestSourceWithImportsCgoPreambleKept(t *testing.T) {
+	const src = `package p
+
+// #include <stdlib.h>
+import "C"
+
+func f() {
+	C.free(nil)
+}
+`
+	out, err := SourceWithImports([]byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "// #include <stdlib.h>\nimport \"C\"") {
+		t.Errorf("expected cgo preamble comment to stay directly above import \"C\", got:\n%s", out)
+	}
+}
+
+func TestSourceWithImportsDocCommentKept(t *testing.T) {
+	const src = `package p
+
+// Standard library imports.
+import (
+	"fmt"
+)
+
+func f() {
+	fmt.Println("hi")
+	widget.New()
+}
+`
+	resolver := stubResolver{"widget": "example.com/acme/widget"}
+	out, err := SourceWithImports([]byte(src), &ImportOptions{Resolver: resolver})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "// Standard library imports.\nimport (") {
+		t.Errorf("expected the import block's doc comment to survive the regroup, got:\n%s", out)
+	}
+}
+
+func TestSourceWithImportsGrouping(t *testing.T) {
+	const src = `package p
+
+import (
+	"fmt"
+	"github.com/foo/bar"
+)
+
+func f() {
+	fmt.Println("hi")
+	bar.Do()
+}
+`
+	out, err := SourceWithImports([]byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdlib := strings.Index(string(out), `"fmt"`)
+	thirdParty := strings.Index(string(out), `"github.com/foo/bar"`)
+	if stdlib < 0 || thirdParty < 0 || stdlib > thirdParty {
+		t.Fatalf("expected stdlib group before third-party group, got:\n%s", out)
+	}
+	if !strings.Contains(string(out), "\"fmt\"\n\n\t\"github.com/foo/bar\"") {
+		t.Errorf("expected a blank line between the stdlib and third-party groups, got:\n%s", out)
+	}
+}
+
+func TestSourceWithImportsFormatOnly(t *testing.T) {
+	const src = `package p
+
+func f() {
+	fmt.Println("hi")
+}
+`
+	out, err := SourceWithImports([]byte(src), &ImportOptions{FormatOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), `"fmt"`) {
+		t.Errorf("FormatOnly should not add imports, got:\n%s", out)
+	}
+}
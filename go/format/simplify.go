@@ -0,0 +1,228 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"io"
+)
+
+// Options controls the rewrites SourceWithOptions and NodeWithOptions
+// apply before formatting.
+type Options struct {
+	// Simplify enables the same AST simplifications cmd/gofmt applies
+	// under the -s flag: composite literals of the form
+	// []T{T{...}, T{...}} collapse to []T{{...}, {...}}, s[a:len(s)]
+	// becomes s[a:], and for _ = range x becomes for range x.
+	Simplify bool
+
+	// ShortenEmptyInterface rewrites bare interface{} type expressions
+	// to the predeclared alias any. It only takes effect when format
+	// was built with a go1.18 or later toolchain, since any did not
+	// exist as a predeclared identifier before Go 1.18; on an older
+	// toolchain this field is accepted but has no effect.
+	ShortenEmptyInterface bool
+
+	// Rewriters, if non-empty, run after the Simplify rewrites (if
+	// enabled) and before formatting, each in turn, on the parsed
+	// *ast.File. A rewriter must not touch nodes carrying comments it
+	// does not also move, or the comments will be printed in the
+	// wrong place or dropped.
+	Rewriters []func(*ast.File)
+}
+
+// SourceWithOptions formats src like Source, additionally applying the
+// rewrites described by opts. opts may be nil, which is equivalent to
+// Source.
+func SourceWithOptions(src []byte, opts *Options) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, adjust, adjustIndent, err := Parse(fset, "", src, true)
+	if err != nil {
+		return nil, err
+	}
+
+	applyOptions(file, opts)
+
+	return Format(fset, file, adjust, adjustIndent, src, config)
+}
+
+// NodeWithOptions formats node like Node, additionally applying the
+// rewrites described by opts. Rewrites only run when node is an
+// *ast.File or wraps one, since the simplifications and Rewriters
+// operate on whole files. opts may be nil, which is equivalent to
+// Node.
+func NodeWithOptions(dst io.Writer, fset *token.FileSet, node interface{}, opts *Options) error {
+	var file *ast.File
+	switch n := node.(type) {
+	case *ast.File:
+		file = n
+	case *printer.CommentedNode:
+		file, _ = n.Node.(*ast.File)
+	}
+	if file != nil {
+		applyOptions(file, opts)
+	}
+	return Node(dst, fset, node)
+}
+
+func applyOptions(file *ast.File, opts *Options) {
+	if opts == nil {
+		return
+	}
+	if opts.Simplify {
+		simplify(file)
+	}
+	if opts.ShortenEmptyInterface {
+		shortenInterfaces(file)
+	}
+	for _, rewrite := range opts.Rewriters {
+		rewrite(file)
+	}
+}
+
+// simplify applies gofmt -s's AST simplifications to file in place.
+func simplify(f *ast.File) {
+	ast.Inspect(f, simplifyCompositeLit)
+	ast.Inspect(f, simplifySliceExpr)
+	ast.Inspect(f, simplifyRangeStmt)
+}
+
+// simplifyCompositeLit collapses composite literals of the form
+// []T{T{...}, T{...}} (or map[K]T{k: T{...}}) into []T{{...}, {...}},
+// and, when the element type is a pointer, []*T{&T{...}, &T{...}}
+// into []*T{{...}, {...}}, mirroring cmd/gofmt's -s flag. Each element
+// is considered on its own: an element that isn't a nested composite
+// literal of the outer literal's element type (a plain variable, say)
+// is left untouched, and simplification continues with the remaining
+// elements rather than abandoning the whole literal.
+func simplifyCompositeLit(n ast.Node) bool {
+	outer, ok := n.(*ast.CompositeLit)
+	if !ok {
+		return true
+	}
+
+	var eltType ast.Expr
+	switch typ := outer.Type.(type) {
+	case *ast.ArrayType:
+		eltType = typ.Elt
+	case *ast.MapType:
+		eltType = typ.Value
+	default:
+		return true
+	}
+
+	for i, x := range outer.Elts {
+		// Allow for T{...} directly, or K: T{...} inside a map literal.
+		lit := x
+		if kv, ok := x.(*ast.KeyValueExpr); ok {
+			lit = kv.Value
+		}
+
+		// Allow for &T{...} too, when the element type is *T: a
+		// composite literal with neither a type nor an address-of
+		// operator implicitly takes the address of its element type,
+		// so &T{...} collapses all the way to {...}, not &{...}.
+		want := eltType
+		if u, ok := lit.(*ast.UnaryExpr); ok && u.Op == token.AND {
+			star, ok := eltType.(*ast.StarExpr)
+			if !ok {
+				continue
+			}
+			lit, want = u.X, star.X
+		}
+
+		inner, ok := lit.(*ast.CompositeLit)
+		if !ok || inner.Type == nil || !matchesType(inner.Type, want) {
+			continue
+		}
+		var simplified ast.Expr = &ast.CompositeLit{
+			Lbrace: inner.Lbrace,
+			Elts:   inner.Elts,
+			Rbrace: inner.Rbrace,
+		}
+		if kv, ok := x.(*ast.KeyValueExpr); ok {
+			kv.Value = simplified
+		} else {
+			outer.Elts[i] = simplified
+		}
+	}
+	return true
+}
+
+// matchesType reports whether a and b denote the same (unparenthesized,
+// textually identical) type expression. It is conservative: anything
+// it cannot prove identical, it treats as different.
+func matchesType(a, b ast.Expr) bool {
+	switch a := a.(type) {
+	case *ast.Ident:
+		b, ok := b.(*ast.Ident)
+		return ok && a.Name == b.Name
+	case *ast.SelectorExpr:
+		b, ok := b.(*ast.SelectorExpr)
+		if !ok {
+			return false
+		}
+		ax, aok := a.X.(*ast.Ident)
+		bx, bok := b.X.(*ast.Ident)
+		return aok && bok && ax.Name == bx.Name && a.Sel.Name == b.Sel.Name
+	case *ast.StarExpr:
+		b, ok := b.(*ast.StarExpr)
+		return ok && matchesType(a.X, b.X)
+	default:
+		return false
+	}
+}
+
+// simplifySliceExpr rewrites s[a:len(s)] to s[a:], as cmd/gofmt does
+// under -s, whenever the length argument is an unambiguous call to the
+// builtin len on the exact expression being sliced.
+func simplifySliceExpr(n ast.Node) bool {
+	s, ok := n.(*ast.SliceExpr)
+	if !ok {
+		return true
+	}
+	if s.Slice3 || s.High == nil {
+		return true
+	}
+	call, ok := s.High.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return true
+	}
+	fun, ok := call.Fun.(*ast.Ident)
+	if !ok || fun.Name != "len" || fun.Obj != nil {
+		return true
+	}
+	if !identicalExpr(call.Args[0], s.X) {
+		return true
+	}
+	s.High = nil
+	return true
+}
+
+// identicalExpr reports whether a and b are both the same identifier,
+// the only expression shape simplifySliceExpr needs to compare.
+func identicalExpr(a, b ast.Expr) bool {
+	ai, aok := a.(*ast.Ident)
+	bi, bok := b.(*ast.Ident)
+	return aok && bok && ai.Name == bi.Name
+}
+
+// simplifyRangeStmt rewrites for _ = range x into for range x, as
+// cmd/gofmt does under -s.
+func simplifyRangeStmt(n ast.Node) bool {
+	r, ok := n.(*ast.RangeStmt)
+	if !ok {
+		return true
+	}
+	if r.Value != nil || r.Key == nil || r.Tok != token.ASSIGN {
+		return true
+	}
+	if id, ok := r.Key.(*ast.Ident); ok && id.Name == "_" {
+		r.Key = nil
+	}
+	return true
+}
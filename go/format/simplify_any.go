@@ -0,0 +1,61 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package format
+
+import "go/ast"
+
+// shortenInterfaces rewrites bare, method-less interface{} type
+// expressions in f to the predeclared alias any, wherever
+// ShortenEmptyInterface led simplify's caller here. It is built only
+// under go1.18 and later, since any wasn't a predeclared identifier
+// before then; see simplify_noany.go for the fallback.
+func shortenInterfaces(f *ast.File) {
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.Field:
+			node.Type = shortenEmptyInterface(node.Type)
+		case *ast.ValueSpec:
+			node.Type = shortenEmptyInterface(node.Type)
+		case *ast.TypeSpec:
+			node.Type = shortenEmptyInterface(node.Type)
+		case *ast.ArrayType:
+			node.Elt = shortenEmptyInterface(node.Elt)
+		case *ast.MapType:
+			node.Key = shortenEmptyInterface(node.Key)
+			node.Value = shortenEmptyInterface(node.Value)
+		case *ast.ChanType:
+			node.Value = shortenEmptyInterface(node.Value)
+		case *ast.StarExpr:
+			node.X = shortenEmptyInterface(node.X)
+		case *ast.Ellipsis:
+			node.Elt = shortenEmptyInterface(node.Elt)
+		case *ast.TypeAssertExpr:
+			node.Type = shortenEmptyInterface(node.Type)
+		case *ast.CallExpr:
+			node.Fun = shortenEmptyInterface(node.Fun)
+		case *ast.IndexExpr:
+			node.Index = shortenEmptyInterface(node.Index)
+		case *ast.IndexListExpr:
+			for i, x := range node.Indices {
+				node.Indices[i] = shortenEmptyInterface(x)
+			}
+		}
+		return true
+	})
+}
+
+// shortenEmptyInterface replaces t with the predeclared identifier
+// any if t is a bare interface{} with no methods and no embeds, and
+// returns t unchanged otherwise.
+func shortenEmptyInterface(t ast.Expr) ast.Expr {
+	it, ok := t.(*ast.InterfaceType)
+	if !ok || it.Methods == nil || len(it.Methods.List) != 0 {
+		return t
+	}
+	return ast.NewIdent("any")
+}
@@ -0,0 +1,17 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !go1.18
+// +build !go1.18
+
+package format
+
+import "go/ast"
+
+// shortenInterfaces is a no-op on toolchains older than Go 1.18: any
+// isn't a predeclared identifier there, so rewriting interface{} to it
+// would introduce an undefined reference. ShortenEmptyInterface has
+// no effect unless format is built with a go1.18+ toolchain; see
+// simplify_any.go for the real implementation.
+func shortenInterfaces(f *ast.File) {}
@@ -0,0 +1,104 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSourceWithOptionsSimplifyCompositeLit(t *testing.T) {
+	const src = `package p
+
+type T struct{ X int }
+
+var s = []T{T{1}, x, T{2}}
+`
+	out, err := SourceWithOptions([]byte(src), &Options{Simplify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "[]T{{1}, x, {2}}") {
+		t.Errorf("expected matching elements to simplify independently of x, got:\n%s", out)
+	}
+}
+
+func TestSourceWithOptionsSimplifyCompositeLitPointer(t *testing.T) {
+	const src = `package p
+
+type T struct{ X int }
+
+var s = []*T{&T{1}, x, &T{2}}
+`
+	out, err := SourceWithOptions([]byte(src), &Options{Simplify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "[]*T{{1}, x, {2}}") {
+		t.Errorf("expected &T{...} elements of a []*T to simplify independently of x, got:\n%s", out)
+	}
+}
+
+func TestSourceWithOptionsSimplifySliceExpr(t *testing.T) {
+	const src = `package p
+
+var _ = s[a:len(s)]
+`
+	out, err := SourceWithOptions([]byte(src), &Options{Simplify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "s[a:]") {
+		t.Errorf("expected s[a:len(s)] to simplify to s[a:], got:\n%s", out)
+	}
+}
+
+func TestSourceWithOptionsSimplifyRangeStmt(t *testing.T) {
+	const src = `package p
+
+func f(x []int) {
+	for _ = range x {
+	}
+}
+`
+	out, err := SourceWithOptions([]byte(src), &Options{Simplify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "for range x") {
+		t.Errorf("expected for _ = range x to simplify to for range x, got:\n%s", out)
+	}
+}
+
+func TestSourceWithOptionsShortenEmptyInterface(t *testing.T) {
+	const src = `package p
+
+func f(x interface{}) interface{} {
+	return x
+}
+`
+	out, err := SourceWithOptions([]byte(src), &Options{ShortenEmptyInterface: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(out)
+	if strings.Contains(got, "interface{}") {
+		t.Errorf("expected interface{} to shorten to any, got:\n%s", got)
+	}
+}
+
+func TestSourceWithOptionsNil(t *testing.T) {
+	const src = `package p
+
+var s = []int{1, 2, 3}
+`
+	out, err := SourceWithOptions([]byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != src {
+		t.Errorf("nil Options should behave like Source, got:\n%s", out)
+	}
+}
@@ -20,25 +20,29 @@ func GoReduceLinesFromReader(r io.Reader, numWorkers int, reduceFunc func(string
 		inChan := make(chan string)
 		var wg sync.WaitGroup
 
-		// TODO: See if I can create goroutines alongside with the work, up to a max number, rather than all in advance
-		// Create numWorkers goroutines
-		for worker := 0; worker < numWorkers; worker++ {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				for {
-					if in, ok := <-inChan; ok {
-						if out := reduceFunc(in); out != nil {
-							outChan <- out
-						}
-					} else {
-						return
-					}
+		worker := func() {
+			defer wg.Done()
+			for in := range inChan {
+				if out := reduceFunc(in); out != nil {
+					outChan <- out
 				}
-			}()
+			}
 		}
 
-		ProcessLinesFromReader(r, func(in string) { inChan <- in })
+		// Spawn a worker alongside each line, up to numWorkers, instead
+		// of starting the whole pool before any work exists: with an
+		// unbuffered inChan a line can only be sent once a worker is
+		// there to receive it, so a short input never pays for idle
+		// goroutines it will never feed.
+		spawned := 0
+		ProcessLinesFromReader(r, func(in string) {
+			if spawned < numWorkers {
+				spawned++
+				wg.Add(1)
+				go worker()
+			}
+			inChan <- in
+		})
 		close(inChan)
 		wg.Wait()
 		close(outChan)
@@ -52,28 +56,35 @@ func GoReduce(inChan <-chan interface{}, numWorkers int, reduceFunc func(interfa
 	outChan := make(chan interface{})
 
 	go func() {
+		jobs := make(chan interface{})
 		var wg sync.WaitGroup
 
-		// TODO: See if I can create goroutines alongside with the work, up to a max number, rather than all in advance
-		// Create numWorkers goroutines
-		for worker := 0; worker < numWorkers; worker++ {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				for {
-					in, ok := <-inChan
-					if !ok {
-						return
-					}
-
-					out := reduceFunc(in)
-					if out != nil {
-						outChan <- out
-					}
+		worker := func() {
+			defer wg.Done()
+			for in := range jobs {
+				if out := reduceFunc(in); out != nil {
+					outChan <- out
 				}
-			}()
+			}
 		}
 
+		// Spawn a worker alongside each job, up to numWorkers, instead
+		// of starting the whole pool before any work exists: with an
+		// unbuffered jobs channel a job can only be sent once a worker
+		// is there to receive it, so a short input never pays for idle
+		// goroutines it will never feed. This relay also lets GoReduce
+		// bound concurrency even though inChan is supplied by, and
+		// paced by, the caller.
+		spawned := 0
+		for in := range inChan {
+			if spawned < numWorkers {
+				spawned++
+				wg.Add(1)
+				go worker()
+			}
+			jobs <- in
+		}
+		close(jobs)
 		wg.Wait()
 		close(outChan)
 	}()